@@ -0,0 +1,92 @@
+package main
+
+import "sync"
+
+// ttyCommon holds the bookkeeping that's identical across every real
+// console/terminal Tty backend regardless of platform: the mirror
+// channels, the resize callback, and the lifecycle plumbing used to stop
+// the background resize-watcher goroutine. tty_unix.go and
+// tty_windows.go each embed it so the SIGWINCH-based and
+// ReadConsoleInput-based resize watchers share one implementation of
+// NotifyResize and shutdown.
+type ttyCommon struct {
+	cb        func()
+	stopQ     chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+	l         sync.Mutex
+	mirror    chan []byte // tee of bytes returned from Read
+	mirrorOut chan []byte // tee of bytes passed to Write
+}
+
+// closeStopQ closes stopQ at most once, so Stop and Close can both call
+// it without racing each other into a double-close panic.
+func (c *ttyCommon) closeStopQ() {
+	c.stopOnce.Do(func() {
+		if c.stopQ != nil {
+			close(c.stopQ)
+		}
+	})
+}
+
+// newStopQ replaces stopQ with a fresh, open channel and resets stopOnce
+// so the new channel can be closed again later. Start must call this
+// rather than just assigning stopQ directly: ttyCommon lives as long as
+// the stdIoTty itself, so a spent stopOnce from a prior Start/Stop cycle
+// (tcell's Screen.Suspend/Resume does exactly one per suspend) would
+// otherwise silently no-op every later closeStopQ call.
+func (c *ttyCommon) newStopQ() chan struct{} {
+	c.stopOnce = sync.Once{}
+	c.stopQ = make(chan struct{})
+	return c.stopQ
+}
+
+func (c *ttyCommon) NotifyResize(cb func()) {
+	c.l.Lock()
+	c.cb = cb
+	c.l.Unlock()
+}
+
+// fireResize invokes the callback registered via NotifyResize, if any.
+func (c *ttyCommon) fireResize() {
+	c.l.Lock()
+	cb := c.cb
+	c.l.Unlock()
+	if cb != nil {
+		cb()
+	}
+}
+
+// mirrorRead forwards b to the input mirror channel, if one was configured.
+func (c *ttyCommon) mirrorRead(b []byte) {
+	mirrorSend(c.mirror, b)
+}
+
+// mirrorWrite forwards b to the output mirror channel, if one was configured.
+func (c *ttyCommon) mirrorWrite(b []byte) {
+	mirrorSend(c.mirrorOut, b)
+}
+
+// mirrorSend delivers b to ch without ever blocking the Read/Write call
+// it tees: if ch's buffer is full, it drops the oldest queued chunk to
+// make room rather than deadlocking against a consumer that has stopped
+// draining it. On an unbuffered ch with no receiver ready, b itself is
+// dropped.
+func mirrorSend(ch chan []byte, b []byte) {
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- b:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- b:
+	default:
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestMirrorSendNil(t *testing.T) {
+	// Must not panic on a nil channel (the "mirroring disabled" case).
+	mirrorSend(nil, []byte("x"))
+}
+
+func TestMirrorSendBuffered(t *testing.T) {
+	ch := make(chan []byte, 1)
+	mirrorSend(ch, []byte("a"))
+	if got := string(<-ch); got != "a" {
+		t.Fatalf("got %q, want %q", got, "a")
+	}
+}
+
+func TestMirrorSendDropsOldestWhenFull(t *testing.T) {
+	ch := make(chan []byte, 1)
+	mirrorSend(ch, []byte("old"))
+	mirrorSend(ch, []byte("new"))
+
+	got := string(<-ch)
+	if got != "new" {
+		t.Fatalf("got %q, want %q (oldest chunk should have been dropped)", got, "new")
+	}
+	select {
+	case extra := <-ch:
+		t.Fatalf("unexpected extra chunk %q in channel", extra)
+	default:
+	}
+}
@@ -0,0 +1,180 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/creack/pty"
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/term"
+)
+
+func init() {
+	registerTtyKind(TtyKindPty, func(opts TtyOptions) (tcell.Tty, error) {
+		return NewPtyTty(opts)
+	})
+}
+
+// ptyTty is a tcell.Tty implementation backed by a spawned child process
+// running under a pseudo-terminal, rather than the process's own
+// stdin/stdout. It lets witty run headless (no controlling terminal
+// required) and is what backs TtyKindPty.
+type ptyTty struct {
+	cmd    *exec.Cmd
+	master *os.File
+	cb     func()
+	mirror chan []byte
+	l      sync.Mutex
+	closed bool
+
+	sig   chan os.Signal
+	stopQ chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewPtyTty spawns opts.PtyCommand (defaulting to $SHELL, then "sh") under
+// a fresh pty and returns a tcell.Tty driven by the pty master. The child
+// inherits no controlling terminal of its own beyond the pty, so witty can
+// record or mirror its I/O without ever touching a real tty device.
+func NewPtyTty(opts TtyOptions) (tcell.Tty, error) {
+	name := opts.PtyCommand
+	if name == "" {
+		name = os.Getenv("SHELL")
+	}
+	if name == "" {
+		name = "sh"
+	}
+
+	cmd := exec.Command(name, opts.PtyArgs...)
+	master, err := pty.Start(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start pty: %w", err)
+	}
+
+	tty := &ptyTty{
+		cmd:    cmd,
+		master: master,
+		mirror: opts.Mirror,
+		sig:    make(chan os.Signal, 1),
+		stopQ:  make(chan struct{}),
+	}
+	go tty.reap()
+
+	signal.Notify(tty.sig, syscall.SIGWINCH)
+	tty.wg.Add(1)
+	go tty.watchResize()
+
+	return tty, nil
+}
+
+// reap waits for the child so it never lingers as a zombie once the pty
+// session ends, whether the child exits on its own or Close kills it.
+func (tty *ptyTty) reap() {
+	_ = tty.cmd.Wait()
+}
+
+// watchResize forwards the host terminal's SIGWINCH to the pty child via
+// TIOCSWINSZ: a pty has no controlling terminal of its own to receive the
+// signal, so without this the child never learns the real terminal was
+// resized.
+func (tty *ptyTty) watchResize() {
+	defer tty.wg.Done()
+	for {
+		select {
+		case <-tty.sig:
+			if w, h, err := term.GetSize(int(os.Stdin.Fd())); err == nil {
+				_ = tty.Resize(w, h)
+			}
+		case <-tty.stopQ:
+			return
+		}
+	}
+}
+
+func (tty *ptyTty) Read(b []byte) (int, error) {
+	n, err := tty.master.Read(b)
+	if err != nil {
+		return n, err
+	}
+	mirrorSend(tty.mirror, b[:n])
+	return n, nil
+}
+
+func (tty *ptyTty) Write(b []byte) (int, error) {
+	return tty.master.Write(b)
+}
+
+func (tty *ptyTty) Close() error {
+	tty.l.Lock()
+	if tty.closed {
+		tty.l.Unlock()
+		return nil
+	}
+	tty.closed = true
+	signal.Stop(tty.sig)
+	close(tty.stopQ)
+	tty.l.Unlock()
+
+	// Wait outside the lock: watchResize's Resize call needs tty.l too,
+	// so holding it here while waiting would deadlock against a resize
+	// in flight when Close is called.
+	tty.wg.Wait()
+
+	_ = tty.master.Close()
+	if tty.cmd.Process != nil {
+		_ = tty.cmd.Process.Kill()
+	}
+	return nil
+}
+
+func (tty *ptyTty) Start() error {
+	return nil
+}
+
+func (tty *ptyTty) Drain() error {
+	return nil
+}
+
+func (tty *ptyTty) Stop() error {
+	return nil
+}
+
+func (tty *ptyTty) WindowSize() (int, int, error) {
+	size, err := pty.GetsizeFull(tty.master)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(size.Cols), int(size.Rows), nil
+}
+
+func (tty *ptyTty) NotifyResize(cb func()) {
+	tty.l.Lock()
+	tty.cb = cb
+	tty.l.Unlock()
+}
+
+// Resize applies w x h to the pty via TIOCSWINSZ and fires the resize
+// callback registered through NotifyResize, mirroring the SIGWINCH
+// forwarding a real controlling terminal would provide.
+func (tty *ptyTty) Resize(w, h int) error {
+	if tty.master == nil {
+		return errors.New("pty: not started")
+	}
+	if err := pty.Setsize(tty.master, &pty.Winsize{Rows: uint16(h), Cols: uint16(w)}); err != nil {
+		return err
+	}
+	tty.l.Lock()
+	cb := tty.cb
+	tty.l.Unlock()
+	if cb != nil {
+		cb()
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	registerTtyKind(TtyKindRecorder, func(opts TtyOptions) (tcell.Tty, error) {
+		return NewRecorderTty(opts.RecordPath)
+	})
+}
+
+// castHeader is the first line of an asciinema v2 cast file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env"`
+}
+
+// castEvent is one subsequent line of an asciinema v2 cast file: elapsed
+// seconds since session start, a single-character event code ("o" for
+// output, "i" for input, "r" for resize), and the payload.
+type castEvent struct {
+	elapsed float64
+	code    string
+	data    string
+}
+
+func (e castEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal([3]interface{}{e.elapsed, e.code, e.data})
+}
+
+// recorderTty wraps a stdIoTty and tees its input, output, and resize
+// events into an asciinema v2 JSON cast file, so a witty session can be
+// replayed with any asciinema-compatible player.
+type recorderTty struct {
+	tcell.Tty
+
+	start time.Time
+
+	fileL sync.Mutex
+	f     *os.File
+	w     *bufio.Writer
+
+	cbL    sync.Mutex
+	userCB func()
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRecorderTty wraps the stdin/stdout Tty backend with a session
+// recorder that writes an asciinema v2 cast file to path.
+func NewRecorderTty(path string) (tcell.Tty, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cast file %s: %w", path, err)
+	}
+
+	mirrorIn := make(chan []byte, 64)
+	mirrorOut := make(chan []byte, 64)
+
+	inner, err := newStdIoTty(mirrorIn, mirrorOut)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	rec := &recorderTty{
+		Tty:   inner,
+		start: time.Now(),
+		f:     f,
+		w:     bufio.NewWriter(f),
+		done:  make(chan struct{}),
+	}
+
+	w, h, _ := inner.WindowSize()
+	if err := rec.writeHeader(w, h); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	inner.NotifyResize(rec.onResize)
+	go rec.pump(mirrorIn, mirrorOut)
+
+	return rec, nil
+}
+
+// NotifyResize overrides the embedded Tty's method so the recorder keeps
+// its own resize hook on the wrapped tty (to log "r" rows) while still
+// honoring whatever callback the caller installs.
+func (r *recorderTty) NotifyResize(cb func()) {
+	r.cbL.Lock()
+	r.userCB = cb
+	r.cbL.Unlock()
+}
+
+func (r *recorderTty) onResize() {
+	if w, h, err := r.Tty.WindowSize(); err == nil {
+		r.writeEvent(castEvent{r.elapsed(), "r", fmt.Sprintf("%dx%d", w, h)})
+	}
+	r.cbL.Lock()
+	cb := r.userCB
+	r.cbL.Unlock()
+	if cb != nil {
+		cb()
+	}
+}
+
+// Close closes done exactly once, so a second Close (tcell's shutdown
+// path can call Close more than once) doesn't panic trying to close an
+// already-closed channel.
+func (r *recorderTty) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+	err := r.Tty.Close()
+
+	r.fileL.Lock()
+	_ = r.w.Flush()
+	_ = r.f.Close()
+	r.fileL.Unlock()
+
+	return err
+}
+
+func (r *recorderTty) elapsed() float64 {
+	return time.Since(r.start).Seconds()
+}
+
+func (r *recorderTty) pump(mirrorIn, mirrorOut chan []byte) {
+	for {
+		select {
+		case b, ok := <-mirrorIn:
+			if !ok {
+				return
+			}
+			r.writeEvent(castEvent{r.elapsed(), "i", string(b)})
+		case b, ok := <-mirrorOut:
+			if !ok {
+				return
+			}
+			r.writeEvent(castEvent{r.elapsed(), "o", string(b)})
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *recorderTty) writeHeader(w, h int) error {
+	hdr := castHeader{
+		Version:   2,
+		Width:     w,
+		Height:    h,
+		Timestamp: r.start.Unix(),
+		Env: map[string]string{
+			"SHELL": os.Getenv("SHELL"),
+			"TERM":  os.Getenv("TERM"),
+		},
+	}
+	b, err := json.Marshal(hdr)
+	if err != nil {
+		return err
+	}
+
+	r.fileL.Lock()
+	defer r.fileL.Unlock()
+	if _, err := r.w.Write(b); err != nil {
+		return err
+	}
+	return r.w.WriteByte('\n')
+}
+
+func (r *recorderTty) writeEvent(e castEvent) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	r.fileL.Lock()
+	defer r.fileL.Unlock()
+	_, _ = r.w.Write(b)
+	_ = r.w.WriteByte('\n')
+	_ = r.w.Flush()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCastEventMarshalJSON(t *testing.T) {
+	e := castEvent{elapsed: 1.5, code: "o", data: "hi"}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got []interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d elements, want 3: %v", len(got), got)
+	}
+	if got[0].(float64) != e.elapsed {
+		t.Errorf("elapsed = %v, want %v", got[0], e.elapsed)
+	}
+	if got[1].(string) != e.code {
+		t.Errorf("code = %v, want %v", got[1], e.code)
+	}
+	if got[2].(string) != e.data {
+		t.Errorf("data = %v, want %v", got[2], e.data)
+	}
+}
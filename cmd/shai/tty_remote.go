@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	registerTtyKind(TtyKindRemote, func(opts TtyOptions) (tcell.Tty, error) {
+		return NewRemoteTty(opts.Conn)
+	})
+}
+
+// Remote frame types. Each frame on the wire is a one-byte type followed
+// by a payload: a uint32 length-prefixed byte string for input/output,
+// two big-endian uint16s (cols, rows) for resize, and nothing for
+// start/stop.
+const (
+	remoteFrameInput byte = iota
+	remoteFrameOutput
+	remoteFrameResize
+	remoteFrameStart
+	remoteFrameStop
+)
+
+// maxRemoteFrameLen bounds the length prefix of an input/output frame.
+// Without a cap, a peer could send a length of up to ~4GB and force
+// readFrame to allocate that much before the mismatch is ever noticed.
+const maxRemoteFrameLen = 1 << 20
+
+// remoteTty is a tcell.Tty backend that speaks a small framed protocol
+// over any io.ReadWriter (a net.Conn, a WebSocket wrapper, or an
+// in-process pipe), so a tcell UI can be driven from a browser, an
+// SSH-forwarded socket, or a test harness without a real terminal on
+// either end.
+type remoteTty struct {
+	rw io.ReadWriter
+
+	inbox   chan []byte
+	pending []byte // leftover from a chunk that didn't fit in the caller's last Read
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+
+	l          sync.Mutex
+	cb         func()
+	cols, rows uint16
+
+	writeL sync.Mutex
+}
+
+// NewRemoteTty wraps rw in a tcell.Tty that reads and writes the framed
+// remote protocol: input/output byte frames, resize frames, and a
+// start/stop handshake.
+func NewRemoteTty(rw io.ReadWriter) (tcell.Tty, error) {
+	if rw == nil {
+		return nil, errors.New("remote tty: nil connection")
+	}
+	return &remoteTty{
+		rw:    rw,
+		inbox: make(chan []byte, 64),
+		done:  make(chan struct{}),
+	}, nil
+}
+
+func (tty *remoteTty) Start() error {
+	if err := tty.writeFrame(remoteFrameStart, nil); err != nil {
+		return err
+	}
+
+	tty.wg.Add(1)
+	go tty.recvLoop()
+	return nil
+}
+
+// recvLoop demultiplexes frames off the wire until it hits an error or a
+// stop frame, dispatching input bytes to Read via inbox and resize
+// frames to the NotifyResize callback.
+func (tty *remoteTty) recvLoop() {
+	defer tty.wg.Done()
+	defer tty.stop()
+
+	for {
+		typ, payload, err := tty.readFrame()
+		if err != nil {
+			return
+		}
+		switch typ {
+		case remoteFrameInput:
+			// Unlike the best-effort mirror tees used for recording,
+			// inbox is the only path real keystrokes take into Read, so
+			// a slow consumer must stall the peer rather than silently
+			// lose input.
+			select {
+			case tty.inbox <- payload:
+			case <-tty.done:
+				return
+			}
+		case remoteFrameResize:
+			if len(payload) != 4 {
+				continue
+			}
+			tty.l.Lock()
+			tty.cols = binary.BigEndian.Uint16(payload[0:2])
+			tty.rows = binary.BigEndian.Uint16(payload[2:4])
+			cb := tty.cb
+			tty.l.Unlock()
+			if cb != nil {
+				cb()
+			}
+		case remoteFrameStop:
+			return
+		}
+	}
+}
+
+// Read is only ever called from the single goroutine tcell reads on, so
+// pending needs no locking of its own.
+func (tty *remoteTty) Read(b []byte) (int, error) {
+	if len(tty.pending) == 0 {
+		select {
+		case chunk, ok := <-tty.inbox:
+			if !ok {
+				return 0, io.EOF
+			}
+			tty.pending = chunk
+		case <-tty.done:
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(b, tty.pending)
+	tty.pending = tty.pending[n:]
+	return n, nil
+}
+
+func (tty *remoteTty) Write(b []byte) (int, error) {
+	if err := tty.writeFrame(remoteFrameOutput, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (tty *remoteTty) Drain() error {
+	return nil
+}
+
+func (tty *remoteTty) Stop() error {
+	_ = tty.writeFrame(remoteFrameStop, nil)
+	tty.stop()
+	tty.wg.Wait()
+	return nil
+}
+
+func (tty *remoteTty) Close() error {
+	tty.stop()
+	return nil
+}
+
+// stop closes done (unblocking any pending Read) and closes the
+// underlying connection, exactly once. recvLoop's blocking io.ReadFull
+// has no way to see done close on its own, so without also closing rw
+// here, a peer that never reciprocates a stop frame (crashed, a dumb
+// relay, a test harness) would leave recvLoop parked forever and Stop's
+// wg.Wait would hang.
+func (tty *remoteTty) stop() {
+	tty.once.Do(func() {
+		close(tty.done)
+		if c, ok := tty.rw.(io.Closer); ok {
+			_ = c.Close()
+		}
+	})
+}
+
+func (tty *remoteTty) WindowSize() (int, int, error) {
+	tty.l.Lock()
+	defer tty.l.Unlock()
+	return int(tty.cols), int(tty.rows), nil
+}
+
+func (tty *remoteTty) NotifyResize(cb func()) {
+	tty.l.Lock()
+	tty.cb = cb
+	tty.l.Unlock()
+}
+
+// writeFrame serializes and writes a single frame. Writes are
+// serialized with writeL since tcell may call Write concurrently with
+// the protocol frames (start, stop) emitted elsewhere.
+func (tty *remoteTty) writeFrame(typ byte, payload []byte) error {
+	tty.writeL.Lock()
+	defer tty.writeL.Unlock()
+
+	if _, err := tty.rw.Write([]byte{typ}); err != nil {
+		return err
+	}
+	if typ == remoteFrameResize {
+		_, err := tty.rw.Write(payload)
+		return err
+	}
+	if typ != remoteFrameInput && typ != remoteFrameOutput {
+		return nil
+	}
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(payload)))
+	if _, err := tty.rw.Write(length[:]); err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+	_, err := tty.rw.Write(payload)
+	return err
+}
+
+// readFrame reads and deserializes a single frame from the wire.
+func (tty *remoteTty) readFrame() (byte, []byte, error) {
+	var typBuf [1]byte
+	if _, err := io.ReadFull(tty.rw, typBuf[:]); err != nil {
+		return 0, nil, err
+	}
+	typ := typBuf[0]
+
+	switch typ {
+	case remoteFrameStart, remoteFrameStop:
+		return typ, nil, nil
+	case remoteFrameResize:
+		payload := make([]byte, 4)
+		if _, err := io.ReadFull(tty.rw, payload); err != nil {
+			return 0, nil, err
+		}
+		return typ, payload, nil
+	case remoteFrameInput, remoteFrameOutput:
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(tty.rw, lenBuf[:]); err != nil {
+			return 0, nil, err
+		}
+		n := binary.BigEndian.Uint32(lenBuf[:])
+		if n > maxRemoteFrameLen {
+			return 0, nil, fmt.Errorf("remote tty: frame length %d exceeds max %d", n, maxRemoteFrameLen)
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(tty.rw, payload); err != nil {
+			return 0, nil, err
+		}
+		return typ, payload, nil
+	default:
+		return 0, nil, errors.New("remote tty: unknown frame type")
+	}
+}
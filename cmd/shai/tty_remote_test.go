@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestWriteFrameReadFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	tty := &remoteTty{rw: &buf}
+
+	if err := tty.writeFrame(remoteFrameOutput, []byte("hello")); err != nil {
+		t.Fatalf("writeFrame: %v", err)
+	}
+
+	typ, payload, err := tty.readFrame()
+	if err != nil {
+		t.Fatalf("readFrame: %v", err)
+	}
+	if typ != remoteFrameOutput {
+		t.Errorf("typ = %v, want %v", typ, remoteFrameOutput)
+	}
+	if string(payload) != "hello" {
+		t.Errorf("payload = %q, want %q", payload, "hello")
+	}
+}
+
+func TestReadFrameStartStopHaveNoPayload(t *testing.T) {
+	for _, typ := range []byte{remoteFrameStart, remoteFrameStop} {
+		var buf bytes.Buffer
+		tty := &remoteTty{rw: &buf}
+		if err := tty.writeFrame(typ, nil); err != nil {
+			t.Fatalf("writeFrame: %v", err)
+		}
+
+		gotTyp, payload, err := tty.readFrame()
+		if err != nil {
+			t.Fatalf("readFrame: %v", err)
+		}
+		if gotTyp != typ {
+			t.Errorf("typ = %v, want %v", gotTyp, typ)
+		}
+		if payload != nil {
+			t.Errorf("payload = %v, want nil", payload)
+		}
+	}
+}
+
+func TestReadFrameUnknownType(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{0xFF})
+	tty := &remoteTty{rw: buf}
+
+	if _, _, err := tty.readFrame(); err == nil {
+		t.Fatal("expected an error for an unknown frame type")
+	}
+}
+
+func TestReadFrameRejectsOversizeLength(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteByte(remoteFrameInput)
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], maxRemoteFrameLen+1)
+	buf.Write(length[:])
+
+	tty := &remoteTty{rw: &buf}
+	if _, _, err := tty.readFrame(); err == nil {
+		t.Fatal("expected an error for a frame length above maxRemoteFrameLen")
+	}
+}
+
+func TestReadFrameResizePayload(t *testing.T) {
+	var buf bytes.Buffer
+	tty := &remoteTty{rw: &buf}
+
+	var payload [4]byte
+	binary.BigEndian.PutUint16(payload[0:2], 80)
+	binary.BigEndian.PutUint16(payload[2:4], 24)
+	if err := tty.writeFrame(remoteFrameResize, payload[:]); err != nil {
+		t.Fatalf("writeFrame: %v", err)
+	}
+
+	typ, got, err := tty.readFrame()
+	if err != nil {
+		t.Fatalf("readFrame: %v", err)
+	}
+	if typ != remoteFrameResize {
+		t.Errorf("typ = %v, want %v", typ, remoteFrameResize)
+	}
+	if !bytes.Equal(got, payload[:]) {
+		t.Errorf("payload = %v, want %v", got, payload)
+	}
+}
+
+func TestReadFrameShortReadIsAnError(t *testing.T) {
+	// A frame type byte with no length prefix following it should
+	// surface io.ErrUnexpectedEOF/io.EOF rather than hang or panic.
+	buf := bytes.NewBuffer([]byte{remoteFrameInput})
+	tty := &remoteTty{rw: buf}
+
+	if _, _, err := tty.readFrame(); err != io.ErrUnexpectedEOF && err != io.EOF {
+		t.Fatalf("readFrame err = %v, want io.EOF or io.ErrUnexpectedEOF", err)
+	}
+}
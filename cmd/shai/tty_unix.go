@@ -0,0 +1,240 @@
+//go:build !windows
+
+package main
+
+// Copyright 2021 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// stdIoTty is an implementation of the Tty API based upon stdin/stdout.
+type stdIoTty struct {
+	ttyCommon
+
+	fd    int
+	in    *os.File
+	out   *os.File
+	saved *term.State
+	sig   chan os.Signal
+}
+
+// Read blocks until input arrives, the tty is stopped, or the fd is put
+// into VMIN=0/VTIME=1 mode by Stop, whichever comes first. It checks
+// stopQ between each poll so shutdown doesn't depend on racing a
+// SetReadDeadline call against an in-flight Read.
+func (tty *stdIoTty) Read(b []byte) (int, error) {
+	for {
+		select {
+		case <-tty.stopQ:
+			return 0, io.EOF
+		default:
+		}
+
+		n, err := tty.in.Read(b)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return n, err
+		}
+		if n == 0 {
+			continue
+		}
+
+		tty.mirrorRead(b[:n])
+		return n, nil
+	}
+}
+
+func (tty *stdIoTty) Write(b []byte) (int, error) {
+	n, err := tty.out.Write(b)
+	if err == nil {
+		tty.mirrorWrite(b[:n])
+	}
+	return n, err
+}
+
+// Close unblocks any Read in progress (surfacing io.EOF to it) even if
+// the caller never called Stop, so a consumer reading in its own
+// goroutine can always exit instead of hanging on shutdown.
+func (tty *stdIoTty) Close() error {
+	tty.l.Lock()
+	tty.unblockRead()
+	tty.l.Unlock()
+
+	tty.closeStopQ()
+	return nil
+}
+
+// unblockRead forces any Read currently parked in the blocking
+// tty.in.Read kernel call to return: VMIN=0/VTIME=1 makes a pending read
+// return (with n==0) within 100ms, and the expired deadline makes it
+// return immediately if the raw mode VMIN/VTIME haven't taken effect
+// yet. Callers must hold tty.l. A no-op before Start has ever run.
+func (tty *stdIoTty) unblockRead() {
+	if tty.stopQ == nil {
+		return
+	}
+	_ = tcSetBufParams(tty.fd, 0, 1)
+	_ = tty.in.SetReadDeadline(time.Now())
+}
+
+func (tty *stdIoTty) Start() error {
+	tty.l.Lock()
+	defer tty.l.Unlock()
+
+	// We use stdin/stdout directly and do not open another copy of /dev/tty.
+	// (Reopening /dev/tty is a workaround for unusual macOS behavior when a
+	// subshell closes our own tty device; when using stdin/stdout instead of
+	// /dev/tty this problem is not observed.)
+	var err error
+	tty.in = os.Stdin
+	tty.out = os.Stdout
+	tty.fd = int(tty.in.Fd())
+
+	if !term.IsTerminal(tty.fd) {
+		return errors.New("device is not a terminal")
+	}
+
+	_ = tty.in.SetReadDeadline(time.Time{})
+	saved, err := term.MakeRaw(tty.fd) // also sets vMin and vTime
+	if err != nil {
+		return err
+	}
+	tty.saved = saved
+
+	stopQ := tty.newStopQ()
+	tty.wg.Add(1)
+	go func(stopQ chan struct{}) {
+		defer tty.wg.Done()
+		for {
+			select {
+			case <-tty.sig:
+				tty.fireResize()
+			case <-stopQ:
+				return
+			}
+		}
+	}(stopQ)
+
+	signal.Notify(tty.sig, syscall.SIGWINCH)
+	return nil
+}
+
+func (tty *stdIoTty) Drain() error {
+	_ = tty.in.SetReadDeadline(time.Now())
+	if err := tcSetBufParams(tty.fd, 0, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tty *stdIoTty) Stop() error {
+	tty.l.Lock()
+
+	// unblockRead first: a Read already blocked in the kernel read()
+	// call won't see stopQ close, but VMIN=0/VTIME=1 makes it return
+	// (with n==0, err==nil) within 100ms, at which point the Read loop
+	// re-checks stopQ and exits cleanly.
+	tty.unblockRead()
+
+	if err := term.Restore(tty.fd, tty.saved); err != nil {
+		tty.l.Unlock()
+		return err
+	}
+
+	signal.Stop(tty.sig)
+	tty.closeStopQ()
+	tty.l.Unlock()
+
+	tty.wg.Wait()
+
+	return nil
+}
+
+func (tty *stdIoTty) WindowSize() (int, int, error) {
+	w, h, err := term.GetSize(tty.fd)
+	if err != nil {
+		return 0, 0, err
+	}
+	if w == 0 {
+		w, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	if w == 0 {
+		w = 80 // default
+	}
+	if h == 0 {
+		h, _ = strconv.Atoi(os.Getenv("LINES"))
+	}
+	if h == 0 {
+		h = 25 // default
+	}
+	return w, h, nil
+}
+
+// NewMirrorTty creates a tty that mirrors its input to the given channel
+func NewMirrorTty(mirror chan []byte) (tcell.Tty, error) {
+	return newStdIoTty(mirror, nil)
+}
+
+// newStdIoTty is the shared constructor behind NewMirrorTty and
+// NewRecorderTty: it wires up a stdIoTty with an input mirror, an output
+// mirror, or both.
+func newStdIoTty(mirrorIn, mirrorOut chan []byte) (tcell.Tty, error) {
+	tty := &stdIoTty{
+		ttyCommon: ttyCommon{mirror: mirrorIn, mirrorOut: mirrorOut},
+		sig:       make(chan os.Signal),
+		in:        os.Stdin,
+		out:       os.Stdout,
+	}
+	var err error
+	tty.fd = int(tty.in.Fd())
+	if !term.IsTerminal(tty.fd) {
+		return nil, errors.New("not a terminal")
+	}
+	if tty.saved, err = term.GetState(tty.fd); err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	return tty, nil
+}
+
+// tcSetBufParams is used by the tty driver on UNIX systems to configure the
+// buffering parameters (minimum character count and minimum wait time in tenths of a second.)
+// This also waits for output to drain first.
+func tcSetBufParams(fd int, vMin uint8, vTime uint8) error {
+	_ = syscall.SetNonblock(fd, true)
+	tio, err := unix.IoctlGetTermios(fd, unix.TIOCGETA)
+	if err != nil {
+		return err
+	}
+	tio.Cc[unix.VMIN] = vMin
+	tio.Cc[unix.VTIME] = vTime
+	if err = unix.IoctlSetTermios(fd, unix.TIOCSETAW, tio); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+//go:build windows
+
+package main
+
+// Copyright 2021 The TCell Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/sys/windows"
+)
+
+// errOperationAborted is ERROR_OPERATION_ABORTED (995), returned by a
+// blocked ReadFile once cancelIoEx cancels it.
+const errOperationAborted = syscall.Errno(995)
+
+// golang.org/x/sys/windows has no binding for ReadConsoleInput (only the
+// WINDOW_BUFFER_SIZE_EVENT constant), so watchResize calls kernel32
+// directly. inputRecord mirrors the layout of Win32's INPUT_RECORD: a
+// WORD event type, the compiler-inserted padding to the union's 4-byte
+// alignment, and the union itself. Only eventType is ever read, so the
+// union is sized to the largest member (KEY_EVENT_RECORD, 16 bytes) and
+// treated as opaque.
+type inputRecord struct {
+	eventType uint16
+	_         uint16
+	event     [16]byte
+}
+
+var (
+	modkernel32           = windows.NewLazySystemDLL("kernel32.dll")
+	procReadConsoleInputW = modkernel32.NewProc("ReadConsoleInputW")
+	procCancelIoEx        = modkernel32.NewProc("CancelIoEx")
+)
+
+// readConsoleInputW reads up to len(recs) input records from the console
+// input buffer, blocking until at least one is available.
+func readConsoleInputW(h windows.Handle, recs []inputRecord) (uint32, error) {
+	var n uint32
+	r1, _, e1 := procReadConsoleInputW.Call(
+		uintptr(h),
+		uintptr(unsafe.Pointer(&recs[0])),
+		uintptr(len(recs)),
+		uintptr(unsafe.Pointer(&n)),
+	)
+	if r1 == 0 {
+		return 0, e1
+	}
+	return n, nil
+}
+
+// cancelIoEx cancels all pending I/O issued against h, regardless of
+// which thread issued it, so a Read blocked in ReadFile can be woken up
+// from Stop/Close running on a different goroutine.
+func cancelIoEx(h windows.Handle) error {
+	r1, _, e1 := procCancelIoEx.Call(uintptr(h), 0)
+	if r1 == 0 {
+		return e1
+	}
+	return nil
+}
+
+// stdIoTty is an implementation of the Tty API for Windows consoles
+// (cmd.exe, PowerShell, and Cygwin/MSYS2 ptys that forward a real
+// console). Where the Unix backend relies on termios and SIGWINCH, this
+// one toggles console modes directly and watches for resize records via
+// ReadConsoleInput, but shares the same ttyCommon lifecycle plumbing.
+type stdIoTty struct {
+	ttyCommon
+
+	in       windows.Handle
+	out      windows.Handle
+	savedIn  uint32
+	savedOut uint32
+}
+
+// Read blocks in ReadFile until input arrives or the tty is stopped. A
+// blocked ReadFile doesn't see stopQ close on its own, so Stop/Close
+// call cancelIoEx to abort it, which surfaces as errOperationAborted
+// here and sends the loop back to check stopQ.
+func (tty *stdIoTty) Read(b []byte) (int, error) {
+	for {
+		select {
+		case <-tty.stopQ:
+			return 0, io.EOF
+		default:
+		}
+
+		var n uint32
+		err := windows.ReadFile(tty.in, b, &n, nil)
+		if err != nil {
+			if errors.Is(err, errOperationAborted) {
+				continue
+			}
+			return 0, err
+		}
+		if n == 0 {
+			continue
+		}
+
+		tty.mirrorRead(b[:n])
+		return int(n), nil
+	}
+}
+
+func (tty *stdIoTty) Write(b []byte) (int, error) {
+	var n uint32
+	if err := windows.WriteFile(tty.out, b, &n, nil); err != nil {
+		return 0, err
+	}
+	tty.mirrorWrite(b[:n])
+	return int(n), nil
+}
+
+// Close unblocks any Read in progress (surfacing io.EOF to it) even if
+// the caller never called Stop, so a consumer reading in its own
+// goroutine can always exit instead of hanging on shutdown.
+func (tty *stdIoTty) Close() error {
+	tty.closeStopQ()
+
+	tty.l.Lock()
+	tty.unblockRead()
+	tty.l.Unlock()
+	return nil
+}
+
+// unblockRead cancels any Read currently parked in ReadFile. Callers
+// must hold tty.l. A no-op before Start has ever run.
+func (tty *stdIoTty) unblockRead() {
+	if tty.stopQ == nil {
+		return
+	}
+	_ = cancelIoEx(tty.in)
+}
+
+func (tty *stdIoTty) Start() error {
+	tty.l.Lock()
+	defer tty.l.Unlock()
+
+	var err error
+	tty.in = windows.Handle(os.Stdin.Fd())
+	tty.out = windows.Handle(os.Stdout.Fd())
+
+	if err = windows.GetConsoleMode(tty.in, &tty.savedIn); err != nil {
+		return fmt.Errorf("device is not a console: %w", err)
+	}
+	if err = windows.GetConsoleMode(tty.out, &tty.savedOut); err != nil {
+		return err
+	}
+
+	inMode := tty.savedIn &^ (windows.ENABLE_ECHO_INPUT | windows.ENABLE_LINE_INPUT | windows.ENABLE_PROCESSED_INPUT)
+	inMode |= windows.ENABLE_VIRTUAL_TERMINAL_INPUT | windows.ENABLE_WINDOW_INPUT
+	if err = windows.SetConsoleMode(tty.in, inMode); err != nil {
+		return err
+	}
+
+	outMode := tty.savedOut | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING | windows.ENABLE_PROCESSED_OUTPUT
+	if err = windows.SetConsoleMode(tty.out, outMode); err != nil {
+		return err
+	}
+
+	stopQ := tty.newStopQ()
+	tty.wg.Add(1)
+	go tty.watchResize(stopQ)
+
+	return nil
+}
+
+// watchResize polls the console input buffer for WINDOW_BUFFER_SIZE_EVENT
+// records, which Windows delivers through ReadConsoleInput instead of a
+// SIGWINCH-style signal, and fires the registered resize callback for each.
+func (tty *stdIoTty) watchResize(stopQ chan struct{}) {
+	defer tty.wg.Done()
+
+	var recs [8]inputRecord
+	for {
+		select {
+		case <-stopQ:
+			return
+		default:
+		}
+
+		n, err := readConsoleInputW(tty.in, recs[:])
+		if err != nil {
+			return
+		}
+		for i := uint32(0); i < n; i++ {
+			if recs[i].eventType == windows.WINDOW_BUFFER_SIZE_EVENT {
+				tty.fireResize()
+			}
+		}
+	}
+}
+
+func (tty *stdIoTty) Drain() error {
+	return nil
+}
+
+func (tty *stdIoTty) Stop() error {
+	tty.l.Lock()
+
+	tty.unblockRead()
+
+	if err := windows.SetConsoleMode(tty.in, tty.savedIn); err != nil {
+		tty.l.Unlock()
+		return err
+	}
+	if err := windows.SetConsoleMode(tty.out, tty.savedOut); err != nil {
+		tty.l.Unlock()
+		return err
+	}
+
+	// Cancel again once stopQ is closed: a cancelled ReadFile only
+	// aborts once, so a Read woken by the first cancel may have gone back
+	// into ReadFile before stopQ closed.
+	tty.closeStopQ()
+	tty.unblockRead()
+	tty.l.Unlock()
+
+	tty.wg.Wait()
+
+	return nil
+}
+
+func (tty *stdIoTty) WindowSize() (int, int, error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(tty.out, &info); err != nil {
+		return 0, 0, err
+	}
+	w := int(info.Window.Right-info.Window.Left) + 1
+	h := int(info.Window.Bottom-info.Window.Top) + 1
+	if w <= 0 {
+		w = 80
+	}
+	if h <= 0 {
+		h = 25
+	}
+	return w, h, nil
+}
+
+// NewMirrorTty creates a tty that mirrors its input to the given channel
+func NewMirrorTty(mirror chan []byte) (tcell.Tty, error) {
+	return newStdIoTty(mirror, nil)
+}
+
+// newStdIoTty is the shared constructor behind NewMirrorTty and
+// NewRecorderTty: it wires up a stdIoTty with an input mirror, an output
+// mirror, or both.
+func newStdIoTty(mirrorIn, mirrorOut chan []byte) (tcell.Tty, error) {
+	tty := &stdIoTty{
+		ttyCommon: ttyCommon{mirror: mirrorIn, mirrorOut: mirrorOut},
+		in:        windows.Handle(os.Stdin.Fd()),
+		out:       windows.Handle(os.Stdout.Fd()),
+	}
+	if err := windows.GetConsoleMode(tty.in, &tty.savedIn); err != nil {
+		return nil, errors.New("not a console")
+	}
+	if err := windows.GetConsoleMode(tty.out, &tty.savedOut); err != nil {
+		return nil, errors.New("not a console")
+	}
+	return tty, nil
+}